@@ -8,7 +8,9 @@ package libconfig
 import "C"
 import (
 	"fmt"
+	"os"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
@@ -57,81 +59,182 @@ var mutex *sync.RWMutex
 
 type LibConfig struct {
 	configFile string
-	cConf      C.struct_config_t
+	current    atomic.Pointer[C.struct_config_t]
+	// generation counts config_t swaps performed by readInto/Watcher.reload.
+	// Every Setting derived from c stamps the generation current when it
+	// was created, so Setting.Stale can tell a caller that the config_t its
+	// cSetting points into was replaced by a later reload (see Stale's doc
+	// comment for when that cSetting must no longer be dereferenced).
+	generation atomic.Uint64
+	limits     resourceLimits
+	// includeFunc is guarded by callbackMu (see callback.go), not mutex,
+	// since it's consulted from the cgo include trampoline rather than
+	// from ReadFile/WriteFile's own critical sections.
+	includeFunc func(includeDir, path string) ([]string, error)
 }
 
 type Setting struct {
 	propPath string
 	libConf  *LibConfig
 	cSetting *C.struct_config_setting_t
+	// generation is libConf.generation.Load() at the time this Setting was
+	// created; see Setting.Stale.
+	generation uint64
+}
+
+// newSetting builds a Setting rooted at cSetting, stamping it with libConf's
+// current generation (see LibConfig.generation) so Stale can later detect
+// that a reload destroyed the config_t it points into.
+func newSetting(libConf *LibConfig, cSetting *C.struct_config_setting_t, propPath string) *Setting {
+	s := &Setting{libConf: libConf, cSetting: cSetting, propPath: propPath}
+	if libConf != nil {
+		s.generation = libConf.generation.Load()
+	}
+	return s
+}
+
+// Stale reports whether a ReadFile/ReadString call or Watcher reload has
+// swapped in a new config_t for s.libConf since s was obtained. libconfig
+// frees a config_t's entire setting tree on config_destroy, which readInto
+// calls on every successful swap (see LibConfig.go:readInto) and which
+// ReloadEvent.Old.Destroy calls after a Watcher reload, so s.cSetting then
+// becomes a dangling pointer into freed C memory: calling any method on a
+// stale Setting whose config_t has been destroyed is a use-after-free.
+// Callers that retain a Setting across a reload (e.g. across a Watch loop iteration) must check
+// Stale before using it again, and re-derive it from s.libConf if stale.
+func (s *Setting) Stale() bool {
+	return s.libConf != nil && s.libConf.generation.Load() != s.generation
 }
 
 func NewLibConfig() *LibConfig {
 	conf := &LibConfig{}
 	mutex = new(sync.RWMutex)
-	C.config_init(&conf.cConf)
+	cConf := new(C.struct_config_t)
+	C.config_init(cConf)
+	conf.current.Store(cConf)
 	return conf
 }
 
+// conf returns the config_t currently in effect. LibConfig methods always
+// read through this accessor rather than holding onto a config_t pointer of
+// their own, so a fresh call made after a Watcher-driven reload (see
+// watcher.go) always reaches the newly swapped-in config_t. This does NOT
+// make a previously obtained *Setting reload-safe: its cSetting points
+// directly into the config_t that was current when it was created, and
+// that config_t is destroyed by the swap in ReadFile/ReadString, or by
+// ReloadEvent.Old.Destroy after a Watcher reload, so the Setting can go
+// stale out from under a caller still holding it (see Setting.Stale).
+func (c *LibConfig) conf() *C.struct_config_t {
+	return c.current.Load()
+}
+
 func (c *LibConfig) ConfigLookup(path string) *Setting {
-	setting := &Setting{
-		propPath: path,
-		libConf:  c,
-	}
 	cPath := C.CString(path)
 	defer C.free(unsafe.Pointer(cPath))
-	setting.cSetting = C.config_lookup(&c.cConf, cPath)
-	return setting
+	cSetting := C.config_lookup(c.conf(), cPath)
+	return newSetting(c, cSetting, path)
 }
 
 func (c *LibConfig) Destroy() {
-	C.config_destroy(&c.cConf)
+	C.config_destroy(c.conf())
+}
+
+// readInto parses into a freshly initialized config_t via parse and, only
+// if that succeeds and the result passes the maxDepth/maxSettings limits
+// set with SetLimits, installs it as c's active config, destroying
+// whatever config_t was active before. prepare, if non-nil, runs against
+// the fresh config_t before parse (e.g. to carry over an include
+// directory). A config that fails to parse or violates maxDepth/maxSettings
+// is rejected outright and never becomes visible to lookups; the maxBytes
+// limit is checked by the callers before readInto runs.
+func (c *LibConfig) readInto(prepare func(conf *C.struct_config_t), parse func(conf *C.struct_config_t) C.int, operation string) error {
+	next := new(C.struct_config_t)
+	C.config_init(next)
+	if prepare != nil {
+		prepare(next)
+	}
+	c.installIncludeFunc(next)
+	if rc := int(parse(next)); rc == cConfigFalse {
+		err := errorFromConf(next, operation)
+		forgetConf(next)
+		C.config_destroy(next)
+		return err
+	}
+	if err := c.checkTreeLimits(next); err != nil {
+		forgetConf(next)
+		C.config_destroy(next)
+		return err
+	}
+	if old := c.current.Swap(next); old != nil {
+		c.generation.Add(1)
+		forgetConf(old)
+		C.config_destroy(old)
+	}
+	return nil
 }
 
+// ReadFile parses configFile into a fresh config_t and, once it passes any
+// limits set with SetLimits, installs it as c's active config. A config
+// that is too large to read, or that parses but violates maxDepth/
+// maxSettings, is rejected and never becomes visible to lookups.
 func (c *LibConfig) ReadFile(configFile string) error {
+	if c.limits.maxBytes > 0 {
+		info, err := os.Stat(configFile)
+		if err != nil {
+			return fmt.Errorf("libconfig: ReadFile: %w", err)
+		}
+		if info.Size() > c.limits.maxBytes {
+			return fmt.Errorf("libconfig: ReadFile: %s exceeds %d byte limit", configFile, c.limits.maxBytes)
+		}
+	}
 	mutex.RLock()
-	c.configFile = configFile
-	cFilename := C.CString(c.configFile)
+	defer mutex.RUnlock()
+	cFilename := C.CString(configFile)
 	defer C.free(unsafe.Pointer(cFilename))
-	rc := int(C.config_read_file(&c.cConf, cFilename))
-	if rc == cConfigFalse {
-		return c.error("config_read_file")
+	err := c.readInto(nil, func(conf *C.struct_config_t) C.int {
+		return C.config_read_file(conf, cFilename)
+	}, "config_read_file")
+	if err != nil {
+		return err
 	}
-	mutex.RUnlock()
+	c.configFile = configFile
 	return nil
 }
 
+// ReadString behaves like ReadFile but parses configString directly,
+// honouring the same limits.
 func (c *LibConfig) ReadString(configString string) error {
+	if c.limits.maxBytes > 0 && int64(len(configString)) > c.limits.maxBytes {
+		return fmt.Errorf("libconfig: ReadString: input exceeds %d byte limit", c.limits.maxBytes)
+	}
 	cConfigString := C.CString(configString)
 	defer C.free(unsafe.Pointer(cConfigString))
-	rc := int(C.config_read_string(&c.cConf, cConfigString))
-	if rc == cConfigFalse {
-		return c.error("config_read_string")
-	}
-	return nil
+	return c.readInto(nil, func(conf *C.struct_config_t) C.int {
+		return C.config_read_string(conf, cConfigString)
+	}, "config_read_string")
 }
 
 func (c *LibConfig) WriteFile() error {
 	mutex.Lock()
+	defer mutex.Unlock()
 	cFilename := C.CString(c.configFile)
 	defer C.free(unsafe.Pointer(cFilename))
-	rc := int(C.config_write_file(&c.cConf, cFilename))
+	rc := int(C.config_write_file(c.conf(), cFilename))
 	if rc == cConfigFalse {
 		return c.error("config_write_file")
 	}
-	mutex.Unlock()
 	return nil
 }
 
 func (c *LibConfig) WriteToFile(configFile string) error {
 	mutex.Lock()
+	defer mutex.Unlock()
 	cFilename := C.CString(configFile)
 	defer C.free(unsafe.Pointer(cFilename))
-	rc := int(C.config_write_file(&c.cConf, cFilename))
+	rc := int(C.config_write_file(c.conf(), cFilename))
 	if rc == cConfigFalse {
 		return c.error("config_write_file")
 	}
-	mutex.Unlock()
 	return nil
 }
 
@@ -142,28 +245,28 @@ func (c *LibConfig) ConfigLookupByType(propPath string, valueType ValueType) (in
 	switch valueType {
 	case CConfigTypeInt:
 		var resValue C.int
-		rc := int(C.config_lookup_int(&c.cConf, cPropPath, &resValue))
+		rc := int(C.config_lookup_int(c.conf(), cPropPath, &resValue))
 		if rc == cConfigTrue {
 			return int(resValue), nil
 		}
 		err = c.error("config_lookup_int")
 	case CConfigTypeInt64:
 		var resValue C.longlong
-		rc := int(C.config_lookup_int64(&c.cConf, cPropPath, &resValue))
+		rc := int(C.config_lookup_int64(c.conf(), cPropPath, &resValue))
 		if rc == cConfigTrue {
 			return int64(resValue), nil
 		}
 		err = c.error("config_lookup_int64")
 	case CConfigTypeFloat:
 		var resValue C.double
-		rc := int(C.config_lookup_float(&c.cConf, cPropPath, &resValue))
+		rc := int(C.config_lookup_float(c.conf(), cPropPath, &resValue))
 		if rc == cConfigTrue {
 			return float64(resValue), nil
 		}
 		err = c.error("config_lookup_float")
 	case CConfigTypeBool:
 		var value C.int
-		rc := int(C.config_lookup_bool(&c.cConf, cPropPath, &value))
+		rc := int(C.config_lookup_bool(c.conf(), cPropPath, &value))
 		if rc == cConfigTrue {
 			resValue := false
 			if int(value) == cConfigTrue {
@@ -175,7 +278,7 @@ func (c *LibConfig) ConfigLookupByType(propPath string, valueType ValueType) (in
 	case CConfigTypeString:
 		var resValue *C.char
 		defer C.free(unsafe.Pointer(resValue))
-		rc := int(C.config_lookup_string(&c.cConf, cPropPath, &resValue))
+		rc := int(C.config_lookup_string(c.conf(), cPropPath, &resValue))
 		if rc == cConfigTrue {
 			return C.GoString(resValue), nil
 		}
@@ -185,53 +288,48 @@ func (c *LibConfig) ConfigLookupByType(propPath string, valueType ValueType) (in
 }
 
 func (c *LibConfig) ConfigIncludeDir() string {
-	return string(C.GoString(c.cConf.include_dir))
+	return string(C.GoString(c.conf().include_dir))
 }
 
 func (c *LibConfig) ConfigSetIncludeDir(dir string) {
 	cDir := C.CString(dir)
 	defer C.free(unsafe.Pointer(cDir))
-	C.config_set_include_dir(&c.cConf, cDir)
+	C.config_set_include_dir(c.conf(), cDir)
 }
 
 func (c *LibConfig) ConfigGetOptions() ConfigOption {
-	return ConfigOption(C.config_get_options(&c.cConf))
+	return ConfigOption(C.config_get_options(c.conf()))
 }
 
 func (c *LibConfig) ConfigSetOptions(options int) {
-	C.config_set_options(&c.cConf, C.int(options))
+	C.config_set_options(c.conf(), C.int(options))
 }
 
 func (c *LibConfig) ConfigGetFormat() int {
-	return int(c.cConf.default_format)
+	return int(c.conf().default_format)
 }
 
 func (c *LibConfig) ConfigSetFormat(configFormat ConfigFormat) {
-	c.cConf.default_format = C.short(configFormat)
+	c.conf().default_format = C.short(configFormat)
 }
 
 func (c *LibConfig) ConfigGetTabWidth() int16 {
-	return int16(c.cConf.tab_width)
+	return int16(c.conf().tab_width)
 }
 
 func (c *LibConfig) ConfigSetTabWidth(tabWidth uint16) {
-	c.cConf.tab_width = C.ushort(tabWidth & 0x0F)
+	c.conf().tab_width = C.ushort(tabWidth & 0x0F)
 }
 
 func (c *LibConfig) ConfigSetDestructor(destructor *[0]byte) {
-	C.config_set_destructor(&c.cConf, destructor)
+	C.config_set_destructor(c.conf(), destructor)
 }
 
 func (c *LibConfig) ConfigRootSetting() *Setting {
-	if c.cConf.root == nil {
+	if c.conf().root == nil {
 		return nil
 	}
-	setting := &Setting{
-		libConf:  c,
-		cSetting: c.cConf.root,
-		propPath: string(C.GoString(c.cConf.root.name)),
-	}
-	return setting
+	return newSetting(c, c.conf().root, string(C.GoString(c.conf().root.name)))
 }
 
 func (s *Setting) ConfigSettingGetHook() unsafe.Pointer {
@@ -268,23 +366,14 @@ func (s *Setting) GetConfigSettingParent() *Setting {
 	if s.ConfigSettingIsRoot() {
 		return nil
 	}
-	setting := &Setting{
-		libConf:  s.libConf,
-		cSetting: s.cSetting.parent,
-		propPath: string(C.GoString(s.cSetting.parent.name)),
-	}
-	return setting
+	return newSetting(s.libConf, s.cSetting.parent, string(C.GoString(s.cSetting.parent.name)))
 }
 
 func (s *Setting) ConfigSettingLookup(path string) *Setting {
-	resSetting := &Setting{
-		libConf:  s.libConf,
-		propPath: path,
-	}
 	cPath := C.CString(path)
 	defer C.free(unsafe.Pointer(cPath))
-	resSetting.cSetting = C.config_setting_lookup(s.cSetting, cPath)
-	return resSetting
+	cSetting := C.config_setting_lookup(s.cSetting, cPath)
+	return newSetting(s.libConf, cSetting, path)
 }
 
 func (s *Setting) ConfigSettingGetByType(valueType ValueType) interface{} {
@@ -401,23 +490,15 @@ func (s *Setting) ConfigSettingLookupByType(name string, valueType ValueType) (i
 }
 
 func (s *Setting) ConfigSettingGetElmByIndex(index int) *Setting {
-	setting := &Setting{
-		libConf:  s.libConf,
-		propPath: s.propPath,
-	}
-	setting.cSetting = C.config_setting_get_elem(s.cSetting, C.uint(index))
-	return setting
+	cSetting := C.config_setting_get_elem(s.cSetting, C.uint(index))
+	return newSetting(s.libConf, cSetting, s.propPath)
 }
 
 func (s *Setting) ConfigSettingGetMemberByName(name string) *Setting {
-	setting := &Setting{
-		libConf:  s.libConf,
-		propPath: s.propPath,
-	}
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
-	setting.cSetting = C.config_setting_get_member(s.cSetting, cName)
-	return setting
+	cSetting := C.config_setting_get_member(s.cSetting, cName)
+	return newSetting(s.libConf, cSetting, s.propPath)
 }
 
 func (s *Setting) ConfigSettingGetElmByType(index int, valueType ValueType) interface{} {
@@ -437,11 +518,7 @@ func (s *Setting) ConfigSettingGetElmByType(index int, valueType ValueType) inte
 }
 
 func (s *Setting) ConfigSettingSetElmByType(index int, valueType ValueType, value interface{}) (*Setting, error) {
-	setting := &Setting{
-		libConf:  s.libConf,
-		propPath: s.propPath,
-		cSetting: s.cSetting,
-	}
+	setting := newSetting(s.libConf, s.cSetting, s.propPath)
 	errInfo := ""
 	switch valueType {
 	case CConfigTypeInt:
@@ -489,6 +566,36 @@ func (s *Setting) ConfigSettingSetElmByType(index int, valueType ValueType, valu
 	return nil, setting.libConf.error(errInfo)
 }
 
+// ConfigSettingSetElmValue sets element index of the list/array s, mutating
+// only the in-memory setting tree (no implicit WriteFile, unlike
+// ConfigSettingSetElmByType). Pass index -1 to append rather than replace
+// an existing element.
+func (s *Setting) ConfigSettingSetElmValue(index int, valueType ValueType, value interface{}) (*Setting, error) {
+	var cSetting *C.struct_config_setting_t
+	switch valueType {
+	case CConfigTypeInt:
+		cSetting = C.config_setting_set_int_elem(s.cSetting, C.int(index), C.int(value.(int)))
+	case CConfigTypeInt64:
+		cSetting = C.config_setting_set_int64_elem(s.cSetting, C.int(index), C.longlong(value.(int64)))
+	case CConfigTypeFloat:
+		cSetting = C.config_setting_set_float_elem(s.cSetting, C.int(index), C.double(value.(float64)))
+	case CConfigTypeBool:
+		cValue := 0
+		if value.(bool) {
+			cValue = 1
+		}
+		cSetting = C.config_setting_set_bool_elem(s.cSetting, C.int(index), C.int(cValue))
+	case CConfigTypeString:
+		cValue := C.CString(value.(string))
+		defer C.free(unsafe.Pointer(cValue))
+		cSetting = C.config_setting_set_string_elem(s.cSetting, C.int(index), cValue)
+	}
+	if cSetting == nil {
+		return nil, s.libConf.error("config_setting_set_elem")
+	}
+	return newSetting(s.libConf, cSetting, s.propPath), nil
+}
+
 func (s *Setting) ConfigSettingIndex() int {
 	return int(C.config_setting_index(s.cSetting))
 }
@@ -498,13 +605,10 @@ func (s *Setting) ConfigSettingLength() int {
 }
 
 func (s *Setting) ConfigSettingAdd(name string, valueType ValueType, value interface{}) *Setting {
-	setting := &Setting{
-		libConf: s.libConf,
-	}
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
-	setting.cSetting = C.config_setting_add(s.cSetting, cName, C.int(valueType))
-	setting.propPath = string(C.GoString(setting.cSetting.name))
+	cSetting := C.config_setting_add(s.cSetting, cName, C.int(valueType))
+	setting := newSetting(s.libConf, cSetting, string(C.GoString(cSetting.name)))
 	err := setting.ConfigSettingSetByType(valueType, value)
 	if err != nil {
 		return nil
@@ -512,6 +616,51 @@ func (s *Setting) ConfigSettingAdd(name string, valueType ValueType, value inter
 	return setting
 }
 
+// ConfigSettingAddValue creates a setting named name of the given type under
+// s and assigns value, mutating only the in-memory setting tree. Unlike
+// ConfigSettingAdd, it never calls WriteFile, so it works on a LibConfig
+// that has no configFile yet (e.g. one being built up in memory by Marshal
+// or the encoding bridges).
+func (s *Setting) ConfigSettingAddValue(name string, valueType ValueType, value interface{}) (*Setting, error) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	cSetting := C.config_setting_add(s.cSetting, cName, C.int(valueType))
+	if cSetting == nil {
+		return nil, s.libConf.error("config_setting_add")
+	}
+	setting := newSetting(s.libConf, cSetting, string(C.GoString(cSetting.name)))
+	if err := setting.setScalarValue(valueType, value); err != nil {
+		return nil, err
+	}
+	return setting, nil
+}
+
+// setScalarValue writes value into s's underlying C setting without the
+// implicit WriteFile that ConfigSettingSetByType performs. Group/list/array
+// settings have no scalar value, so valueType values other than the ones
+// listed here are a no-op.
+func (s *Setting) setScalarValue(valueType ValueType, value interface{}) error {
+	switch valueType {
+	case CConfigTypeInt:
+		C.config_setting_set_int(s.cSetting, C.int(value.(int)))
+	case CConfigTypeInt64:
+		C.config_setting_set_int64(s.cSetting, C.longlong(value.(int64)))
+	case CConfigTypeFloat:
+		C.config_setting_set_float(s.cSetting, C.double(value.(float64)))
+	case CConfigTypeBool:
+		cValue := 0
+		if value.(bool) {
+			cValue = 1
+		}
+		C.config_setting_set_bool(s.cSetting, C.int(cValue))
+	case CConfigTypeString:
+		cValue := C.CString(value.(string))
+		defer C.free(unsafe.Pointer(cValue))
+		C.config_setting_set_string(s.cSetting, cValue)
+	}
+	return nil
+}
+
 func (s *Setting) ConfigSettingRemove(name string) int {
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
@@ -523,9 +672,17 @@ func (s *Setting) ConfigSettingRemoveElm(index int) int {
 }
 
 func (c *LibConfig) error(operation string) error {
-	errorText := string(C.GoString(c.cConf.error_text))
-	errorFile := string(C.GoString(c.cConf.error_file))
-	errorLine := int(c.cConf.error_line)
-	errType := CConfigError(int(c.cConf.error_type))
+	return errorFromConf(c.conf(), operation)
+}
+
+// errorFromConf reads the error_* fields directly off conf, rather than
+// c.conf(), so a config_t that failed to parse before ever being installed
+// as c's active config (see ReadFile/ReadString) still reports a useful
+// error.
+func errorFromConf(conf *C.struct_config_t, operation string) error {
+	errorText := string(C.GoString(conf.error_text))
+	errorFile := string(C.GoString(conf.error_file))
+	errorLine := int(conf.error_line)
+	errType := CConfigError(int(conf.error_type))
 	return fmt.Errorf("Error:{file:%s,cfunctioncall:%s,line number:%d,type:%v,message:%s} ", errorFile, operation, errorLine, errType, errorText)
 }
@@ -0,0 +1,142 @@
+package libconfig
+
+/*
+#include <libconfig.h>
+#include <stdlib.h>
+
+extern void goDestructorTrampoline(void *hook);
+extern char **goIncludeTrampoline(config_t *config, char *include_dir, char *path, char **error);
+
+static void libconfig_install_destructor(config_t *config) {
+    config_set_destructor(config, goDestructorTrampoline);
+}
+
+static void libconfig_install_include_func(config_t *config) {
+    config_set_include_func(config, (const char **(*)(config_t *, const char *, const char *, const char **))goIncludeTrampoline);
+}
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+var (
+	callbackMu     sync.Mutex
+	destructorFunc func(unsafe.Pointer)
+	// configOwners maps a live config_t pointer to the *LibConfig whose
+	// SetIncludeFunc resolver should run for it. ReadFile/ReadString/the
+	// Watcher all parse into a freshly allocated config_t on every call
+	// (see LibConfig.readInto), so this is re-keyed on every parse rather
+	// than once at SetIncludeFunc time, or a reload would silently orphan
+	// the registered resolver.
+	configOwners = map[uintptr]*LibConfig{}
+)
+
+// SetDestructor registers fn to run whenever libconfig frees a setting that
+// carries a hook pointer (see Setting.ConfigSettingSetHook), replacing the
+// unusable *[0]byte parameter of the raw ConfigSetDestructor binding with a
+// real Go func backed by a //export trampoline. config_destructor_t only
+// ever hands the trampoline a bare hook pointer, with no way back to the
+// config_t it came from, so — like go-alpm's log/question callbacks — fn is
+// process-wide: the most recently registered one wins across all LibConfig
+// instances. The trampoline is installed only on the config_t current at
+// the time of the call; a config_t swapped in later by ReadFile/ReadString
+// or a Watcher reload does not carry it, so hooks on that new tree are not
+// passed to fn when it is freed.
+func (c *LibConfig) SetDestructor(fn func(unsafe.Pointer)) {
+	callbackMu.Lock()
+	destructorFunc = fn
+	callbackMu.Unlock()
+	C.libconfig_install_destructor(c.conf())
+}
+
+//export goDestructorTrampoline
+func goDestructorTrampoline(hook unsafe.Pointer) {
+	callbackMu.Lock()
+	fn := destructorFunc
+	callbackMu.Unlock()
+	if fn != nil {
+		fn(hook)
+	}
+}
+
+// SetIncludeFunc registers fn as the resolver for `@include` directives
+// encountered while parsing c, letting callers load fragments from
+// non-filesystem sources (an embed.FS, a KV store, ...) without writing C.
+// fn receives the configured include directory and the path named by the
+// directive and returns the fragment(s) to splice in; returning a non-nil
+// error surfaces as a parse error from ReadFile/ReadString.
+//
+// Unlike SetDestructor, config_include_func_t is handed the originating
+// config_t back on every call, so fn is looked up in a handle table keyed
+// by that pointer and multiple LibConfig instances can register distinct
+// resolvers safely. Because a fresh config_t is swapped in on every
+// ReadFile/ReadString/Watcher reload, fn is stored on c itself and
+// reinstalled against each new config_t as it is parsed (see
+// LibConfig.readInto), rather than being tied to the config_t pointer that
+// happened to be current at registration time.
+func (c *LibConfig) SetIncludeFunc(fn func(includeDir, path string) ([]string, error)) {
+	callbackMu.Lock()
+	c.includeFunc = fn
+	callbackMu.Unlock()
+	c.installIncludeFunc(c.conf())
+}
+
+// installIncludeFunc records conf as belonging to c and, if c has a
+// resolver registered, wires up the trampoline on conf so that resolver
+// runs for `@include` directives encountered while conf is being parsed.
+func (c *LibConfig) installIncludeFunc(conf *C.struct_config_t) {
+	callbackMu.Lock()
+	fn := c.includeFunc
+	if fn != nil {
+		configOwners[uintptr(unsafe.Pointer(conf))] = c
+	}
+	callbackMu.Unlock()
+	if fn != nil {
+		C.libconfig_install_include_func(conf)
+	}
+}
+
+// forgetConf drops conf's entry from configOwners once it is no longer
+// live, so the map doesn't accumulate stale pointers to destroyed config_t
+// values across repeated reloads.
+func forgetConf(conf *C.struct_config_t) {
+	callbackMu.Lock()
+	delete(configOwners, uintptr(unsafe.Pointer(conf)))
+	callbackMu.Unlock()
+}
+
+//export goIncludeTrampoline
+func goIncludeTrampoline(config *C.config_t, includeDir, path *C.char, errOut **C.char) **C.char {
+	callbackMu.Lock()
+	owner := configOwners[uintptr(unsafe.Pointer(config))]
+	var fn func(includeDir, path string) ([]string, error)
+	if owner != nil {
+		fn = owner.includeFunc
+	}
+	callbackMu.Unlock()
+	if fn == nil {
+		return nil
+	}
+	fragments, err := fn(C.GoString(includeDir), C.GoString(path))
+	if err != nil {
+		*errOut = C.CString(err.Error())
+		return nil
+	}
+	return goStringsToCArray(fragments)
+}
+
+// goStringsToCArray builds a NULL-terminated char** suitable for returning
+// from config_include_func_t; libconfig takes ownership of the array and
+// its elements and frees them once it is done with the included fragments.
+func goStringsToCArray(strs []string) **C.char {
+	arr := (**C.char)(C.malloc(C.size_t(len(strs)+1) * C.size_t(unsafe.Sizeof(uintptr(0)))))
+	slice := unsafe.Slice(arr, len(strs)+1)
+	for i, s := range strs {
+		slice[i] = C.CString(s)
+	}
+	slice[len(strs)] = nil
+	return arr
+}
@@ -0,0 +1,74 @@
+package libconfig
+
+import "context"
+
+// IncludeFunc resolves an `@include` directive, given the configured
+// include directory and the path requested by the directive, returning the
+// fragment(s) to splice in. It is registered with SetIncludeFuncContext
+// below, which passes it ctx so a caller can cancel a slow resolver (e.g. one backed by a remote KV
+// store) the same way it can cancel a slow file read.
+type IncludeFunc func(ctx context.Context, includeDir, path string) ([]string, error)
+
+// ReadFileContext behaves like ReadFile but aborts if ctx is done before the
+// underlying cgo call returns. Note that config_read_file itself is not
+// interruptible, so a cancelled context stops the caller from waiting on it
+// but does not stop the cgo call already in flight.
+func (c *LibConfig) ReadFileContext(ctx context.Context, configFile string) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- c.ReadFile(configFile)
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-result:
+		return err
+	}
+}
+
+// WriteFileContext behaves like WriteFile but aborts if ctx is done before
+// the underlying cgo call returns.
+func (c *LibConfig) WriteFileContext(ctx context.Context) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- c.WriteFile()
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-result:
+		return err
+	}
+}
+
+// WriteToFileContext behaves like WriteToFile but aborts if ctx is done
+// before the underlying cgo call returns.
+func (c *LibConfig) WriteToFileContext(ctx context.Context, configFile string) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- c.WriteToFile(configFile)
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-result:
+		return err
+	}
+}
+
+// SetIncludeFuncContext registers fn, an IncludeFunc, as c's `@include`
+// resolver (see SetIncludeFunc), checking ctx before every invocation so a
+// caller that cancels ctx gets ctx.Err() back as the include (and so the
+// enclosing ReadFile/ReadString) error instead of fn running against a
+// resolver it no longer wants served. Unlike the other Context variants,
+// this does not stop the caller from waiting on a resolver call already in
+// flight: such a call runs until fn returns, whether or not fn itself
+// watches ctx.
+func (c *LibConfig) SetIncludeFuncContext(ctx context.Context, fn IncludeFunc) {
+	c.SetIncludeFunc(func(includeDir, path string) ([]string, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return fn(ctx, includeDir, path)
+	})
+}
@@ -0,0 +1,115 @@
+// Package tree converts between a libconfig setting tree and a generic
+// Go value tree (maps, slices and scalars), so the json/yaml/toml
+// subpackages can each hand that value straight to their own Marshal
+// without re-walking config_setting_t themselves.
+package tree
+
+import (
+	"fmt"
+
+	"github.com/wing-zacharias/libconfig"
+)
+
+// ToGeneric walks s and returns a map[string]interface{}, []interface{}, or
+// scalar mirroring its shape: groups become maps, lists/arrays become
+// slices, everything else is returned as the Go value libconfig already
+// knows how to produce for that setting type.
+func ToGeneric(s *libconfig.Setting) (interface{}, error) {
+	switch s.GetConfigSettingType() {
+	case libconfig.CConfigTypeGroup:
+		length := s.ConfigSettingLength()
+		out := make(map[string]interface{}, length)
+		for i := 0; i < length; i++ {
+			member := s.ConfigSettingGetElmByIndex(i)
+			value, err := ToGeneric(member)
+			if err != nil {
+				return nil, err
+			}
+			out[member.GetConfigSettingName()] = value
+		}
+		return out, nil
+	case libconfig.CConfigTypeList, libconfig.CConfigTypeArray:
+		length := s.ConfigSettingLength()
+		out := make([]interface{}, length)
+		for i := 0; i < length; i++ {
+			value, err := ToGeneric(s.ConfigSettingGetElmByIndex(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = value
+		}
+		return out, nil
+	default:
+		value := s.ConfigSettingGetByType(s.GetConfigSettingType())
+		if value == nil {
+			return nil, fmt.Errorf("tree: unsupported setting type for %q", s.GetConfigSettingName())
+		}
+		return value, nil
+	}
+}
+
+// FromGeneric creates a setting named name under parent (a group or the
+// document root) from value, recursing into maps and slices. It only ever
+// mutates the in-memory setting tree (via Setting.ConfigSettingAddValue),
+// so FromJSON/FromYAML/FromTOML work on a bare LibConfig with no
+// configFile configured.
+func FromGeneric(parent *libconfig.Setting, name string, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		group, err := parent.ConfigSettingAddValue(name, libconfig.CConfigTypeGroup, nil)
+		if err != nil {
+			return fmt.Errorf("tree: adding group %q: %w", name, err)
+		}
+		for k, elem := range v {
+			if err := FromGeneric(group, k, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		listType := libconfig.CConfigTypeList
+		if len(v) > 0 {
+			if _, isMap := v[0].(map[string]interface{}); !isMap {
+				if _, isSlice := v[0].([]interface{}); !isSlice {
+					listType = libconfig.CConfigTypeArray
+				}
+			}
+		}
+		list, err := parent.ConfigSettingAddValue(name, listType, nil)
+		if err != nil {
+			return fmt.Errorf("tree: adding list %q: %w", name, err)
+		}
+		for i, elem := range v {
+			if err := FromGeneric(list, "", elem); err != nil {
+				return fmt.Errorf("%s[%d]: %w", name, i, err)
+			}
+		}
+		return nil
+	default:
+		valueType := scalarType(v)
+		if valueType == libconfig.CConfigTypeNone {
+			return fmt.Errorf("tree: unsupported value %T for %q", value, name)
+		}
+		if _, err := parent.ConfigSettingAddValue(name, valueType, v); err != nil {
+			return fmt.Errorf("tree: adding %q: %w", name, err)
+		}
+		return nil
+	}
+}
+
+func scalarType(v interface{}) libconfig.ValueType {
+	switch v.(type) {
+	case int:
+		return libconfig.CConfigTypeInt
+	case int64:
+		return libconfig.CConfigTypeInt64
+	case float64, float32:
+		return libconfig.CConfigTypeFloat
+	case bool:
+		return libconfig.CConfigTypeBool
+	case string:
+		return libconfig.CConfigTypeString
+	default:
+		return libconfig.CConfigTypeNone
+	}
+}
@@ -0,0 +1,46 @@
+// Package json converts a libconfig setting tree to and from JSON, letting
+// callers migrate libconfig files to or from tooling that only speaks JSON
+// without duplicating the cgo parser.
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/wing-zacharias/libconfig"
+	"github.com/wing-zacharias/libconfig/encoding/internal/tree"
+)
+
+// ToJSON renders s (and everything beneath it) as JSON. Groups become
+// objects, lists/arrays become arrays, scalars marshal by their Go type.
+func ToJSON(s *libconfig.Setting) ([]byte, error) {
+	value, err := tree.ToGeneric(s)
+	if err != nil {
+		return nil, fmt.Errorf("json: %w", err)
+	}
+	return json.Marshal(value)
+}
+
+// FromJSON parses data as JSON and populates c's root setting with the
+// result, creating groups/lists/arrays/scalars to match. Since JSON has no
+// integer type, all JSON numbers round-trip as CConfigTypeFloat.
+func FromJSON(c *libconfig.LibConfig, data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("json: root document must be an object, got %T", value)
+	}
+	root := c.ConfigRootSetting()
+	if root == nil {
+		return fmt.Errorf("json: config has no root setting")
+	}
+	for name, elem := range obj {
+		if err := tree.FromGeneric(root, name, elem); err != nil {
+			return fmt.Errorf("json: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Package toml converts a libconfig setting tree to and from TOML, letting
+// callers migrate libconfig files to or from tooling that only speaks TOML
+// without duplicating the cgo parser.
+package toml
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/wing-zacharias/libconfig"
+	"github.com/wing-zacharias/libconfig/encoding/internal/tree"
+)
+
+// ToTOML renders s (and everything beneath it) as TOML. s must be a group,
+// since TOML documents are tables at the top level.
+func ToTOML(s *libconfig.Setting) ([]byte, error) {
+	value, err := tree.ToGeneric(s)
+	if err != nil {
+		return nil, fmt.Errorf("toml: %w", err)
+	}
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("toml: %q is not a group, got %T", s.GetConfigSettingName(), value)
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(table); err != nil {
+		return nil, fmt.Errorf("toml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// FromTOML parses data as TOML and populates c's root setting with the
+// result, creating groups/lists/arrays/scalars to match.
+func FromTOML(c *libconfig.LibConfig, data []byte) error {
+	var value map[string]interface{}
+	if err := toml.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("toml: %w", err)
+	}
+	root := c.ConfigRootSetting()
+	if root == nil {
+		return fmt.Errorf("toml: config has no root setting")
+	}
+	for name, elem := range value {
+		if err := tree.FromGeneric(root, name, elem); err != nil {
+			return fmt.Errorf("toml: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package yaml converts a libconfig setting tree to and from YAML, letting
+// callers migrate libconfig files to or from tooling that only speaks YAML
+// without duplicating the cgo parser.
+package yaml
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/wing-zacharias/libconfig"
+	"github.com/wing-zacharias/libconfig/encoding/internal/tree"
+)
+
+// ToYAML renders s (and everything beneath it) as YAML. Groups become
+// mappings, lists/arrays become sequences, scalars marshal by their Go
+// type.
+func ToYAML(s *libconfig.Setting) ([]byte, error) {
+	value, err := tree.ToGeneric(s)
+	if err != nil {
+		return nil, fmt.Errorf("yaml: %w", err)
+	}
+	return yaml.Marshal(value)
+}
+
+// FromYAML parses data as YAML and populates c's root setting with the
+// result, creating groups/lists/arrays/scalars to match.
+func FromYAML(c *libconfig.LibConfig, data []byte) error {
+	var value map[string]interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("yaml: %w", err)
+	}
+	root := c.ConfigRootSetting()
+	if root == nil {
+		return fmt.Errorf("yaml: config has no root setting")
+	}
+	for name, elem := range value {
+		if err := tree.FromGeneric(root, name, normalize(elem)); err != nil {
+			return fmt.Errorf("yaml: %w", err)
+		}
+	}
+	return nil
+}
+
+// normalize rewrites the map[interface{}]interface{} and int nodes that
+// yaml.v3 can produce into the map[string]interface{}/int64 shape that the
+// TOML bridge hands tree.FromGeneric, so YAML integers become
+// CConfigTypeInt64 settings. yaml.v3 only decodes
+// a mapping as map[string]interface{} when every key already looks like a
+// string; a mapping with a non-string-looking key (e.g. "1: foo") decodes
+// as map[interface{}]interface{} instead, even nested under a
+// map[string]interface{} root, so that shape needs its own case too.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			out[k] = normalize(elem)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			out[fmt.Sprint(k)] = normalize(elem)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			out[i] = normalize(elem)
+		}
+		return out
+	case int:
+		return int64(v)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,118 @@
+package libconfig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Scalar is the set of Go types the libconfig C API can read and write
+// directly: CConfigTypeInt/Int64/Float/Bool/String. LookupValue, GetElem and
+// LookupPath are generic over it so callers get a compile-time-checked
+// result instead of the interface{} returned by ConfigSettingLookupByType
+// and friends, and the runtime type-assertion panics (e.g. value.(int) in
+// ConfigSettingSetByType) that come with it.
+type Scalar interface {
+	~int | ~int64 | ~float64 | ~bool | ~string
+}
+
+// valueTypeFor switches on zero's Kind rather than its concrete type, since
+// Scalar's ~int | ~int64 | ... constraint admits named types (e.g. type
+// MyInt int) whose concrete type never matches a plain "case int" — only
+// their underlying kind does.
+func valueTypeFor[T Scalar]() ValueType {
+	var zero T
+	switch reflect.TypeOf(zero).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return CConfigTypeInt
+	case reflect.Int64:
+		return CConfigTypeInt64
+	case reflect.Float32, reflect.Float64:
+		return CConfigTypeFloat
+	case reflect.Bool:
+		return CConfigTypeBool
+	case reflect.String:
+		return CConfigTypeString
+	default:
+		return CConfigTypeNone
+	}
+}
+
+func convertTo[T Scalar](value interface{}) (T, error) {
+	var zero T
+	rv := reflect.ValueOf(value)
+	zt := reflect.TypeOf(zero)
+	if !rv.Type().ConvertibleTo(zt) {
+		return zero, fmt.Errorf("libconfig: cannot convert %T to %s", value, zt)
+	}
+	return rv.Convert(zt).Interface().(T), nil
+}
+
+// LookupValue looks up name under s and returns it as T, failing if the
+// setting is missing or its underlying type doesn't convert to T.
+func LookupValue[T Scalar](s *Setting, name string) (T, error) {
+	var zero T
+	valueType := valueTypeFor[T]()
+	if valueType == CConfigTypeNone {
+		return zero, fmt.Errorf("libconfig: unsupported accessor type %T", zero)
+	}
+	value, err := s.ConfigSettingLookupByType(name, valueType)
+	if err != nil {
+		return zero, err
+	}
+	return convertTo[T](value)
+}
+
+// MustLookupValue is LookupValue but panics instead of returning an error.
+func MustLookupValue[T Scalar](s *Setting, name string) T {
+	value, err := LookupValue[T](s, name)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// GetElem returns element index of the list/array s as T.
+func GetElem[T Scalar](s *Setting, index int) (T, error) {
+	var zero T
+	valueType := valueTypeFor[T]()
+	if valueType == CConfigTypeNone {
+		return zero, fmt.Errorf("libconfig: unsupported accessor type %T", zero)
+	}
+	if length := s.ConfigSettingLength(); index < 0 || index >= length {
+		return zero, fmt.Errorf("libconfig: index %d out of range for %q (len %d)", index, s.GetConfigSettingName(), length)
+	}
+	return convertTo[T](s.ConfigSettingGetElmByType(index, valueType))
+}
+
+// MustGetElem is GetElem but panics instead of returning an error.
+func MustGetElem[T Scalar](s *Setting, index int) T {
+	value, err := GetElem[T](s, index)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// LookupPath resolves a dotted libconfig path from cfg's root, such as
+// "general.access.users.[0]", and returns it as T.
+func LookupPath[T Scalar](cfg *LibConfig, path string) (T, error) {
+	var zero T
+	valueType := valueTypeFor[T]()
+	if valueType == CConfigTypeNone {
+		return zero, fmt.Errorf("libconfig: unsupported accessor type %T", zero)
+	}
+	value, err := cfg.ConfigLookupByType(path, valueType)
+	if err != nil {
+		return zero, err
+	}
+	return convertTo[T](value)
+}
+
+// MustLookupPath is LookupPath but panics instead of returning an error.
+func MustLookupPath[T Scalar](cfg *LibConfig, path string) T {
+	value, err := LookupPath[T](cfg, path)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package libconfig
+
+/*
+   #include <libconfig.h>
+*/
+import "C"
+import (
+	"fmt"
+	"io"
+)
+
+// resourceLimits bounds the size and shape of configs ReadReader (and, once
+// set, ReadFile/ReadString) will accept, so a server parsing configs from an
+// untrusted source under a container cgroup memory limit cannot be made to
+// exhaust it with a pathologically large or deeply nested file.
+type resourceLimits struct {
+	maxBytes    int64
+	maxDepth    int
+	maxSettings int
+}
+
+// SetLimits bounds the input size (maxBytes), nesting depth (maxDepth) and
+// total setting count (maxSettings) that subsequent reads will accept. A
+// zero or negative value leaves that dimension unbounded, except that
+// ReadReader still caps its input at 1 MiB when maxBytes is unset.
+func (c *LibConfig) SetLimits(maxBytes, maxDepth, maxSettings int) {
+	c.limits = resourceLimits{
+		maxBytes:    int64(maxBytes),
+		maxDepth:    maxDepth,
+		maxSettings: maxSettings,
+	}
+}
+
+// ReadReader streams r into a bounded buffer and parses it, so callers can
+// read configs from HTTP bodies, etcd watches, or pipes without first
+// loading an arbitrarily large file into memory. It honours the limits set
+// by SetLimits: the byte limit is enforced while streaming, before a single
+// byte reaches the C parser, and ReadString rejects a result that violates
+// maxDepth/maxSettings before installing it (see LibConfig.readInto).
+func (c *LibConfig) ReadReader(r io.Reader) error {
+	limit := c.limits.maxBytes
+	if limit <= 0 {
+		limit = 1 << 20 // 1MiB default guard against unbounded reads
+	}
+	limited := io.LimitReader(r, limit+1)
+	data, err := io.ReadAll(limited)
+	if err != nil {
+		return fmt.Errorf("libconfig: ReadReader: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return fmt.Errorf("libconfig: ReadReader: input exceeds %d byte limit", limit)
+	}
+	return c.ReadString(string(data))
+}
+
+// checkTreeLimits validates conf's root setting directly, before conf is
+// installed as c's active config, so a config that is too deep or has too
+// many settings is rejected without ever being exposed to lookups.
+func (c *LibConfig) checkTreeLimits(conf *C.struct_config_t) error {
+	if c.limits.maxDepth <= 0 && c.limits.maxSettings <= 0 {
+		return nil
+	}
+	if conf.root == nil {
+		return nil
+	}
+	root := newSetting(c, conf.root, string(C.GoString(conf.root.name)))
+	count := 0
+	return walkLimits(root, 1, c.limits, &count)
+}
+
+func walkLimits(s *Setting, depth int, limits resourceLimits, count *int) error {
+	*count++
+	if limits.maxSettings > 0 && *count > limits.maxSettings {
+		return fmt.Errorf("libconfig: config exceeds %d setting limit", limits.maxSettings)
+	}
+	if limits.maxDepth > 0 && depth > limits.maxDepth {
+		return fmt.Errorf("libconfig: config exceeds depth limit of %d at %q", limits.maxDepth, s.GetConfigSettingName())
+	}
+	switch s.GetConfigSettingType() {
+	case CConfigTypeGroup, CConfigTypeList, CConfigTypeArray:
+		length := s.ConfigSettingLength()
+		for i := 0; i < length; i++ {
+			if err := walkLimits(s.ConfigSettingGetElmByIndex(i), depth+1, limits, count); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package libconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReadStringRejectsOversizedInput checks that a maxBytes limit set with
+// SetLimits is enforced by ReadString before the input ever reaches the C
+// parser.
+func TestReadStringRejectsOversizedInput(t *testing.T) {
+	cfg := NewLibConfig()
+	defer cfg.Destroy()
+	cfg.SetLimits(10, 0, 0)
+
+	err := cfg.ReadString(`name = "this string is definitely longer than ten bytes";`)
+	if err == nil {
+		t.Fatal("expected ReadString to reject input over the byte limit")
+	}
+}
+
+// TestReadStringRejectsTooDeep checks that a maxDepth limit is enforced
+// against the parsed tree before it is installed as the active config.
+func TestReadStringRejectsTooDeep(t *testing.T) {
+	cfg := NewLibConfig()
+	defer cfg.Destroy()
+	cfg.SetLimits(0, 2, 0)
+
+	err := cfg.ReadString(`a = { b = { c = 1; }; };`)
+	if err == nil {
+		t.Fatal("expected ReadString to reject a config deeper than maxDepth")
+	}
+	if root := cfg.ConfigRootSetting(); root != nil && root.ConfigSettingLength() != 0 {
+		t.Fatal("rejected config must not be left installed as the active config")
+	}
+}
+
+// TestReadFileRejectsOversizedFile checks that ReadFile enforces maxBytes
+// via a stat of the file, without ever opening it for parsing.
+func TestReadFileRejectsOversizedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.cfg")
+	content := []byte(`name = "this string is definitely longer than ten bytes";`)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg := NewLibConfig()
+	defer cfg.Destroy()
+	cfg.SetLimits(10, 0, 0)
+
+	if err := cfg.ReadFile(path); err == nil {
+		t.Fatal("expected ReadFile to reject a file over the byte limit")
+	}
+}
@@ -0,0 +1,257 @@
+package libconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// structTag describes the parsed form of a `libconfig:"..."` struct tag.
+type structTag struct {
+	name      string
+	omitempty bool
+	skip      bool
+}
+
+func parseStructTag(field reflect.StructField) structTag {
+	raw, ok := field.Tag.Lookup("libconfig")
+	if !ok {
+		return structTag{name: field.Name}
+	}
+	if raw == "-" {
+		return structTag{skip: true}
+	}
+	parts := strings.Split(raw, ",")
+	tag := structTag{name: parts[0]}
+	if tag.name == "" {
+		tag.name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			tag.omitempty = true
+		}
+	}
+	return tag
+}
+
+// Unmarshal walks the root setting tree and populates v, which must be a
+// pointer to a struct, using `libconfig:"name,omitempty"` struct tags to
+// map groups, lists and arrays onto the matching Go fields.
+func (c *LibConfig) Unmarshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("libconfig: Unmarshal requires a non-nil pointer, got %T", v)
+	}
+	root := c.ConfigRootSetting()
+	if root == nil {
+		return fmt.Errorf("libconfig: Unmarshal called before a configuration was read")
+	}
+	return unmarshalSetting(root, rv.Elem())
+}
+
+func unmarshalSetting(s *Setting, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Struct:
+		return unmarshalStruct(s, rv)
+	case reflect.Slice:
+		return unmarshalSlice(s, rv)
+	case reflect.Map:
+		return unmarshalMap(s, rv)
+	default:
+		return unmarshalScalar(s, rv)
+	}
+}
+
+func unmarshalStruct(s *Setting, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := parseStructTag(field)
+		if tag.skip {
+			continue
+		}
+		member := s.ConfigSettingGetMemberByName(tag.name)
+		if member == nil || member.cSetting == nil {
+			continue
+		}
+		if err := unmarshalSetting(member, rv.Field(i)); err != nil {
+			return fmt.Errorf("libconfig: field %q: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func unmarshalSlice(s *Setting, rv reflect.Value) error {
+	length := s.ConfigSettingLength()
+	elemType := rv.Type().Elem()
+	out := reflect.MakeSlice(rv.Type(), length, length)
+	for i := 0; i < length; i++ {
+		elemSetting := s.ConfigSettingGetElmByIndex(i)
+		if elemType.Kind() == reflect.Struct {
+			if err := unmarshalSetting(elemSetting, out.Index(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		value := s.ConfigSettingGetElmByType(i, toValueType(elemType.Kind()))
+		if err := assignScalar(out.Index(i), value); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	rv.Set(out)
+	return nil
+}
+
+func unmarshalMap(s *Setting, rv reflect.Value) error {
+	if rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("libconfig: unsupported map key type %s", rv.Type().Key())
+	}
+	length := s.ConfigSettingLength()
+	out := reflect.MakeMapWithSize(rv.Type(), length)
+	elemType := rv.Type().Elem()
+	for i := 0; i < length; i++ {
+		member := s.ConfigSettingGetElmByIndex(i)
+		key := reflect.ValueOf(member.GetConfigSettingName())
+		elem := reflect.New(elemType).Elem()
+		if err := unmarshalSetting(member, elem); err != nil {
+			return err
+		}
+		out.SetMapIndex(key, elem)
+	}
+	rv.Set(out)
+	return nil
+}
+
+func unmarshalScalar(s *Setting, rv reflect.Value) error {
+	value := s.ConfigSettingGetByType(toValueType(rv.Kind()))
+	return assignScalar(rv, value)
+}
+
+func assignScalar(rv reflect.Value, value interface{}) error {
+	if value == nil {
+		return fmt.Errorf("libconfig: no value for kind %s", rv.Kind())
+	}
+	converted := reflect.ValueOf(value)
+	if !converted.Type().ConvertibleTo(rv.Type()) {
+		return fmt.Errorf("libconfig: cannot assign %T to %s", value, rv.Type())
+	}
+	rv.Set(converted.Convert(rv.Type()))
+	return nil
+}
+
+// toValueType maps a Go reflect.Kind onto the libconfig ValueType used to
+// drive ConfigSettingGetByType/ConfigSettingGetElmByType.
+func toValueType(k reflect.Kind) ValueType {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return CConfigTypeInt
+	case reflect.Int64:
+		return CConfigTypeInt64
+	case reflect.Float32, reflect.Float64:
+		return CConfigTypeFloat
+	case reflect.Bool:
+		return CConfigTypeBool
+	case reflect.String:
+		return CConfigTypeString
+	default:
+		return CConfigTypeNone
+	}
+}
+
+// Marshal creates settings under the root group for each exported field of
+// v (a struct or pointer to struct), mirroring the `libconfig:"..."` tags
+// honoured by Unmarshal. It only ever adds settings, so callers should
+// start from a fresh LibConfig to avoid name collisions with anything
+// already in the tree; it builds the tree entirely in memory and never
+// touches disk, so c need not have a configFile configured.
+func (c *LibConfig) Marshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Errorf("libconfig: Marshal requires a non-nil pointer, got %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("libconfig: Marshal requires a struct or pointer to struct, got %T", v)
+	}
+	root := c.ConfigRootSetting()
+	if root == nil {
+		return fmt.Errorf("libconfig: Marshal called before the config root was initialized")
+	}
+	return marshalStruct(root, rv)
+}
+
+func marshalStruct(s *Setting, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := parseStructTag(field)
+		if tag.skip {
+			continue
+		}
+		fv := rv.Field(i)
+		if tag.omitempty && fv.IsZero() {
+			continue
+		}
+		if err := marshalField(s, tag.name, fv); err != nil {
+			return fmt.Errorf("libconfig: field %q: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func marshalField(s *Setting, name string, fv reflect.Value) error {
+	switch fv.Kind() {
+	case reflect.Struct:
+		group, err := s.ConfigSettingAddValue(name, CConfigTypeGroup, nil)
+		if err != nil {
+			return fmt.Errorf("adding group %q: %w", name, err)
+		}
+		return marshalStruct(group, fv)
+	case reflect.Slice, reflect.Array:
+		listType := CConfigTypeList
+		if fv.Len() > 0 && toValueType(fv.Index(0).Kind()) != CConfigTypeNone {
+			listType = CConfigTypeArray
+		}
+		list, err := s.ConfigSettingAddValue(name, listType, nil)
+		if err != nil {
+			return fmt.Errorf("adding list %q: %w", name, err)
+		}
+		for i := 0; i < fv.Len(); i++ {
+			elem := fv.Index(i)
+			if elem.Kind() == reflect.Struct {
+				elemSetting, err := list.ConfigSettingAddValue("", CConfigTypeGroup, nil)
+				if err != nil {
+					return fmt.Errorf("element %d: %w", i, err)
+				}
+				if err := marshalStruct(elemSetting, elem); err != nil {
+					return err
+				}
+				continue
+			}
+			// config_setting_set_*_elem only accepts an already-in-bounds
+			// index or -1 to append; the list was just created empty, so
+			// every element is appended rather than set by position.
+			if _, err := list.ConfigSettingSetElmValue(-1, toValueType(elem.Kind()), elem.Interface()); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return nil
+	default:
+		valueType := toValueType(fv.Kind())
+		if valueType == CConfigTypeNone {
+			return fmt.Errorf("unsupported kind %s", fv.Kind())
+		}
+		if _, err := s.ConfigSettingAddValue(name, valueType, fv.Interface()); err != nil {
+			return fmt.Errorf("adding %q: %w", name, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package libconfig
+
+import "testing"
+
+type testInnerSettings struct {
+	Host string `libconfig:"host"`
+	Port int    `libconfig:"port"`
+}
+
+type testSettings struct {
+	Name    string            `libconfig:"name"`
+	Count   int64             `libconfig:"count"`
+	Ratio   float64           `libconfig:"ratio"`
+	Enabled bool              `libconfig:"enabled"`
+	Tags    []string          `libconfig:"tags"`
+	Server  testInnerSettings `libconfig:"server"`
+}
+
+// TestMarshalUnmarshalRoundTrip exercises Marshal building a tree entirely
+// in memory (no configFile set) and Unmarshal reading it back, covering
+// scalars, a nested struct/group, and a scalar slice/list.
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	in := testSettings{
+		Name:    "svc",
+		Count:   42,
+		Ratio:   3.5,
+		Enabled: true,
+		Tags:    []string{"a", "b", "c"},
+		Server:  testInnerSettings{Host: "localhost", Port: 8080},
+	}
+
+	cfg := NewLibConfig()
+	defer cfg.Destroy()
+
+	if err := cfg.Marshal(&in); err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var out testSettings
+	if err := cfg.Unmarshal(&out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if out.Name != in.Name || out.Count != in.Count || out.Ratio != in.Ratio || out.Enabled != in.Enabled {
+		t.Fatalf("scalar fields did not round-trip: got %+v, want %+v", out, in)
+	}
+	if out.Server != in.Server {
+		t.Fatalf("nested struct did not round-trip: got %+v, want %+v", out.Server, in.Server)
+	}
+	if len(out.Tags) != len(in.Tags) {
+		t.Fatalf("Tags length = %d, want %d", len(out.Tags), len(in.Tags))
+	}
+	for i, tag := range in.Tags {
+		if out.Tags[i] != tag {
+			t.Fatalf("Tags[%d] = %q, want %q", i, out.Tags[i], tag)
+		}
+	}
+}
+
+// TestMarshalRejectsNonStruct checks the documented error path for callers
+// passing something other than a struct or pointer to struct.
+func TestMarshalRejectsNonStruct(t *testing.T) {
+	cfg := NewLibConfig()
+	defer cfg.Destroy()
+
+	if err := cfg.Marshal(42); err == nil {
+		t.Fatal("expected Marshal to reject a non-struct value")
+	}
+}
@@ -0,0 +1,175 @@
+package libconfig
+
+/*
+   #include <libconfig.h>
+*/
+import "C"
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ReloadEvent reports the result of a hot-reload triggered by Watch. Watch
+// sends no event for the initial ReadFile, so Old is always set. On a
+// successful reload, Old is a
+// distinct snapshot of the config_t that was active immediately before the
+// swap, so the caller can diff it against New; the watcher hands ownership
+// of that snapshot to the caller rather than freeing it itself, since Old
+// and New are meant to coexist while the caller compares them. Callers that
+// no longer need Old should call Old.Destroy() to release it. Err is set
+// instead of New when the reload failed to parse, in which case the
+// LibConfig keeps serving the previously loaded configuration and Old is
+// simply that same, still-current LibConfig.
+//
+// New config_t swaps are atomic at the LibConfig level, but any *Setting a
+// caller obtained from New's underlying LibConfig before a later reload is
+// not: its cSetting points into the config_t that reload handed out as
+// that event's Old, and it dangles once Old.Destroy frees it.
+// Check Setting.Stale before using a Setting retained across a Watch loop
+// iteration, and re-derive it from the LibConfig if it reports stale.
+type ReloadEvent struct {
+	Old *LibConfig
+	New *LibConfig
+	Err error
+}
+
+// Watcher observes the file passed to ReadFile (and any files discovered in
+// ConfigIncludeDir) for changes, parsing each one into a fresh config_t and
+// atomically swapping it into c so a fresh ConfigLookup* call always sees a
+// consistent snapshot. A *Setting obtained before a reload is not itself
+// reload-safe — see Setting.Stale.
+type Watcher struct {
+	c       *LibConfig
+	fsWatch *fsnotify.Watcher
+	events  chan ReloadEvent
+}
+
+// Watch starts watching c's underlying file (and include directory, if any)
+// for changes and returns a channel of ReloadEvent. The watch stops and the
+// channel is closed when ctx is done. Watch does not return its Watcher, so
+// a caller has no Close to call; cancelling ctx is the way to stop it.
+func (c *LibConfig) Watch(ctx context.Context) (<-chan ReloadEvent, error) {
+	if c.configFile == "" {
+		return nil, fmt.Errorf("libconfig: Watch requires a config previously loaded with ReadFile")
+	}
+	fsWatch, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("libconfig: creating watcher: %w", err)
+	}
+	if err := fsWatch.Add(c.configFile); err != nil {
+		fsWatch.Close()
+		return nil, fmt.Errorf("libconfig: watching %s: %w", c.configFile, err)
+	}
+	if dir := c.ConfigIncludeDir(); dir != "" {
+		if err := fsWatch.Add(dir); err != nil {
+			fsWatch.Close()
+			return nil, fmt.Errorf("libconfig: watching include dir %s: %w", dir, err)
+		}
+	}
+	w := &Watcher{
+		c:       c,
+		fsWatch: fsWatch,
+		events:  make(chan ReloadEvent),
+	}
+	go w.run(ctx)
+	return w.events, nil
+}
+
+func (w *Watcher) run(ctx context.Context) {
+	defer close(w.events)
+	defer w.fsWatch.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.fsWatch.Events:
+			if !ok {
+				return
+			}
+			if !w.relevant(event) {
+				continue
+			}
+			w.reload(ctx)
+		case err, ok := <-w.fsWatch.Errors:
+			if !ok {
+				return
+			}
+			w.send(ctx, ReloadEvent{Old: w.c, Err: err})
+		}
+	}
+}
+
+func (w *Watcher) relevant(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+		return false
+	}
+	if filepath.Clean(event.Name) == filepath.Clean(w.c.configFile) {
+		return true
+	}
+	dir := w.c.ConfigIncludeDir()
+	return dir != "" && filepath.Dir(event.Name) == filepath.Clean(dir)
+}
+
+func (w *Watcher) reload(ctx context.Context) {
+	includeDir := w.c.ConfigIncludeDir()
+	cFilename := C.CString(w.c.configFile)
+	defer C.free(unsafe.Pointer(cFilename))
+
+	callbackMu.Lock()
+	includeFunc := w.c.includeFunc
+	callbackMu.Unlock()
+
+	staging := &LibConfig{configFile: w.c.configFile, limits: w.c.limits, includeFunc: includeFunc}
+	err := staging.readInto(func(conf *C.struct_config_t) {
+		if includeDir == "" {
+			return
+		}
+		cDir := C.CString(includeDir)
+		defer C.free(unsafe.Pointer(cDir))
+		C.config_set_include_dir(conf, cDir)
+	}, func(conf *C.struct_config_t) C.int {
+		return C.config_read_file(conf, cFilename)
+	}, "config_read_file")
+	if err != nil {
+		w.send(ctx, ReloadEvent{Old: w.c, Err: err})
+		return
+	}
+
+	// Re-own the freshly parsed config_t under w.c (readInto registered it
+	// under the throwaway staging instance), then swap it in and hand the
+	// pre-swap one back as a distinct LibConfig snapshot, rather than
+	// overwriting w.c in place, so event.Old still reflects what was active
+	// before this reload.
+	parsed := staging.current.Load()
+	w.c.installIncludeFunc(parsed)
+	mutex.Lock()
+	oldConf := w.c.current.Swap(parsed)
+	mutex.Unlock()
+	// Bump w.c's generation so any Setting a caller obtained from w.c before
+	// this reload reports Stale(): oldConf is not destroyed here but handed
+	// to the caller as event.Old, and such a Setting dangles once the caller
+	// calls Old.Destroy (see Setting.Stale).
+	w.c.generation.Add(1)
+	forgetConf(oldConf)
+
+	old := &LibConfig{configFile: w.c.configFile}
+	old.current.Store(oldConf)
+
+	w.send(ctx, ReloadEvent{Old: old, New: w.c})
+}
+
+func (w *Watcher) send(ctx context.Context, event ReloadEvent) {
+	select {
+	case w.events <- event:
+	case <-ctx.Done():
+	}
+}
+
+// Close stops the watcher without waiting for ctx to be cancelled.
+func (w *Watcher) Close() error {
+	return w.fsWatch.Close()
+}
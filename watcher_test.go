@@ -0,0 +1,64 @@
+package libconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWatcherReloadEventOrdering checks that a hot-reload delivers a
+// ReloadEvent whose Old still reflects the pre-reload value and whose New
+// reflects the post-reload value, i.e. that Old is a genuine snapshot and
+// not an alias of New taken after the swap.
+func TestWatcherReloadEventOrdering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.cfg")
+	if err := os.WriteFile(path, []byte(`value = 1;`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cfg := NewLibConfig()
+	defer cfg.Destroy()
+	if err := cfg.ReadFile(path); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := cfg.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`value = 2;`), 0o644); err != nil {
+		t.Fatalf("rewriting %s: %v", path, err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Err != nil {
+			t.Fatalf("unexpected reload error: %v", event.Err)
+		}
+		if event.Old == nil || event.New == nil {
+			t.Fatalf("expected both Old and New to be set, got %+v", event)
+		}
+		oldValue, err := LookupValue[int](event.Old.ConfigRootSetting(), "value")
+		if err != nil {
+			t.Fatalf("looking up Old.value: %v", err)
+		}
+		if oldValue != 1 {
+			t.Fatalf("Old.value = %d, want 1 (the pre-reload value)", oldValue)
+		}
+		newValue, err := LookupValue[int](event.New.ConfigRootSetting(), "value")
+		if err != nil {
+			t.Fatalf("looking up New.value: %v", err)
+		}
+		if newValue != 2 {
+			t.Fatalf("New.value = %d, want 2 (the post-reload value)", newValue)
+		}
+		event.Old.Destroy()
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for reload event")
+	}
+}